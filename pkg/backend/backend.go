@@ -0,0 +1,29 @@
+// Package backend selects which OpenAI-compatible API shape the service
+// talks to, so a deployment can point at something other than the real
+// Assistants API (LocalAI, Ollama, etc. only implement chat completions).
+package backend
+
+import "os"
+
+// Mode is the API shape CreateAssistantWithMetadata and friends use.
+type Mode int
+
+const (
+	// Assistants uses the OpenAI Assistants API (threads, runs, tool calls).
+	Assistants Mode = iota
+	// ChatCompletions emulates a thread over POST /chat/completions for
+	// backends that don't implement the Assistants API.
+	ChatCompletions
+)
+
+// Current is selected at startup from the BACKEND_MODE env var
+// ("chat_completions" to opt in; anything else, including unset, means
+// Assistants).
+var Current = modeFromEnv()
+
+func modeFromEnv() Mode {
+	if os.Getenv("BACKEND_MODE") == "chat_completions" {
+		return ChatCompletions
+	}
+	return Assistants
+}
@@ -0,0 +1,115 @@
+// Package finetune exposes HTTP handlers for turning recorded assistant
+// interactions into a fine-tuning dataset and managing the resulting job.
+// Once a job succeeds, CreateAssistantWithMetadata picks up its model for
+// the trained channel via the ft_model:{channelID} Redis key.
+package finetune
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"Youtube-Learning-Mode-Ai-Service/pkg/handlers"
+	"Youtube-Learning-Mode-Ai-Service/pkg/services"
+)
+
+// CreateJobRequest is the payload for POST /finetune/jobs.
+type CreateJobRequest struct {
+	ChannelID string   `json:"channel_id"`
+	VideoIDs  []string `json:"video_ids"`
+	BaseModel string   `json:"base_model"`
+}
+
+// CreateJobHandler aggregates the interaction history recorded for
+// VideoIDs into a JSONL training file, uploads it, and kicks off a
+// fine-tuning job of BaseModel on it.
+func CreateJobHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handlers.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	trainingFileID, err := services.BuildTrainingFile(req.VideoIDs)
+	if err != nil {
+		handlers.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to build training file: %v", err))
+		return
+	}
+
+	job, err := services.CreateFineTuningJob(trainingFileID, req.BaseModel)
+	if err != nil {
+		handlers.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create fine-tuning job: %v", err))
+		return
+	}
+
+	if err := services.RecordFineTuneJobChannel(job.ID, req.ChannelID); err != nil {
+		log.Printf("Failed to record channel for fine-tuning job %s: %v", job.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetJobHandler handles GET /finetune/jobs/{id}. Once the job succeeds, the
+// resulting model is adopted as the channel's fine-tuned model.
+func GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := jobIDFromPath(r.URL.Path, "/cancel", "/events")
+
+	job, err := services.RetrieveFineTuningJob(jobID)
+	if err != nil {
+		handlers.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve fine-tuning job: %v", err))
+		return
+	}
+
+	if job.Status == "succeeded" && job.FineTunedModel != "" {
+		if channelID, err := services.FineTuneJobChannel(jobID); err == nil && channelID != "" {
+			if err := services.SetFineTunedModel(channelID, job.FineTunedModel); err != nil {
+				log.Printf("Failed to record fine-tuned model for channel %s: %v", channelID, err)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// CancelJobHandler handles POST /finetune/jobs/{id}/cancel.
+func CancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := jobIDFromPath(r.URL.Path, "/cancel", "/events")
+
+	job, err := services.CancelFineTuningJob(jobID)
+	if err != nil {
+		handlers.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to cancel fine-tuning job: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// ListEventsHandler handles GET /finetune/jobs/{id}/events.
+func ListEventsHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := jobIDFromPath(r.URL.Path, "/cancel", "/events")
+
+	events, err := services.ListFineTuningJobEvents(jobID)
+	if err != nil {
+		handlers.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list fine-tuning job events: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// jobIDFromPath pulls the {id} segment out of "/finetune/jobs/{id}" and its
+// "/cancel" and "/events" variants. There's no router in this tree to supply
+// path parameters directly, so handlers parse them from the raw path.
+func jobIDFromPath(path string, trimSuffixes ...string) string {
+	id := strings.TrimPrefix(path, "/finetune/jobs/")
+	for _, suffix := range trimSuffixes {
+		id = strings.TrimSuffix(id, suffix)
+	}
+	return id
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"Youtube-Learning-Mode-Ai-Service/pkg/middleware"
 	"Youtube-Learning-Mode-Ai-Service/pkg/services"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // Request to initialize a GPT session with video context
@@ -21,6 +23,12 @@ type QuestionRequest struct {
 	UserQuestion string `json:"user_question"`
 }
 
+// captionLineApproxSeconds approximates the cue duration of a pre-extracted
+// caption line, which arrives as plain text with no timing of its own.
+// Whisper-transcribed audio carries real per-segment Start/End instead and
+// doesn't need this approximation.
+const captionLineApproxSeconds = 4.0
+
 // Initialize GPT session with video context
 func InitializeGPTSession(w http.ResponseWriter, r *http.Request) {
 	var initReq InitRequest
@@ -29,8 +37,30 @@ func InitializeGPTSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var segments []services.TranscriptSegment
+	if len(initReq.Transcript) == 0 {
+		// No pre-extracted transcript was supplied (no captions, auto-caption
+		// failure, etc.) - transcribe the audio with Whisper instead, which
+		// gives us real per-segment timestamps to chunk on.
+		whisperSegments, err := services.TranscribeVideo(initReq.VideoID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to transcribe video: %v", err), http.StatusInternalServerError)
+			return
+		}
+		segments = whisperSegments
+	} else {
+		segments = make([]services.TranscriptSegment, len(initReq.Transcript))
+		for i, line := range initReq.Transcript {
+			segments[i] = services.TranscriptSegment{
+				Start: float64(i) * captionLineApproxSeconds,
+				End:   float64(i+1) * captionLineApproxSeconds,
+				Text:  line,
+			}
+		}
+	}
+
 	// Call service to initialize GPT session with transcript
-	err := services.CreateGPTSession(initReq.VideoID, initReq.Title, initReq.Channel, initReq.Transcript)
+	err := services.CreateGPTSession(initReq.VideoID, initReq.Title, initReq.Channel, segments)
 	if err != nil {
 		http.Error(w, "Failed to initialize GPT session", http.StatusInternalServerError)
 		return
@@ -41,6 +71,30 @@ func InitializeGPTSession(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "GPT session initialized"})
 }
 
+// TranscribeRequest is the payload for POST /transcribe.
+type TranscribeRequest struct {
+	VideoID string `json:"video_id"`
+}
+
+// TranscribeVideoHandler downloads and transcribes a video's audio with
+// Whisper, independent of initializing a GPT session.
+func TranscribeVideoHandler(w http.ResponseWriter, r *http.Request) {
+	var transcribeReq TranscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&transcribeReq); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	segments, err := services.TranscribeVideo(transcribeReq.VideoID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to transcribe video: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"segments": segments})
+}
+
 // RespondWithError is a helper function to return an error message as JSON
 func RespondWithError(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -48,6 +102,47 @@ func RespondWithError(w http.ResponseWriter, code int, message string) {
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
+// UserCredentials is the payload for both /api/user/register and /api/user/login.
+type UserCredentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RegisterUser creates an account with a bcrypt-hashed password.
+func RegisterUser(w http.ResponseWriter, r *http.Request) {
+	var creds UserCredentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := services.RegisterUser(creds.Email, creds.Password); err != nil {
+		RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Failed to register user: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "user registered"})
+}
+
+// LoginUser verifies credentials and mints a JWT for subsequent requests.
+func LoginUser(w http.ResponseWriter, r *http.Request) {
+	var creds UserCredentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	token, err := services.LoginUser(creds.Email, creds.Password)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, fmt.Sprintf("Failed to log in: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
 // Handle user questions
 func AskGPTQuestion(w http.ResponseWriter, r *http.Request) {
 	var questionReq QuestionRequest
@@ -56,14 +151,67 @@ func AskGPTQuestion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID := middleware.UserID(r)
+
 	// Get GPT response
-	aiResponse, err := services.FetchGPTResponse(questionReq.VideoID, questionReq.UserQuestion)
+	answer, err := services.FetchGPTResponse(userID, questionReq.VideoID, questionReq.UserQuestion)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get AI response: %v", err))
 		return
 	}
 
-	// Respond with AI answer in JSON format
+	// Respond with the AI answer and any transcript citations it cited
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"response": aiResponse})
+	json.NewEncoder(w).Encode(answer)
+}
+
+// AskGPTQuestionStream streams the assistant's answer back to the client as
+// Server-Sent Events instead of blocking until the run completes.
+func AskGPTQuestionStream(w http.ResponseWriter, r *http.Request) {
+	var questionReq QuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&questionReq); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		RespondWithError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	userID := middleware.UserID(r)
+
+	assistantID, err := services.RedisClient.Get(services.Ctx, "assistant_id:"+questionReq.VideoID).Result()
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "No assistant session found for this video")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	_, err = services.AskAssistantQuestionStream(userID, questionReq.VideoID, assistantID, questionReq.UserQuestion, func(delta string) {
+		// A data: line can't contain a literal newline, but model text
+		// frequently does - emit one data: field per line of the delta so a
+		// multi-line fragment survives as a single SSE event instead of its
+		// later lines being dropped as unnamed fields.
+		fmt.Fprint(w, "event: thread.message.delta\n")
+		for _, line := range strings.Split(delta, "\n") {
+			fmt.Fprintf(w, "data: %s\n", line)
+		}
+		fmt.Fprint(w, "\n")
+		flusher.Flush()
+	})
+	if err != nil {
+		fmt.Fprintf(w, "event: thread.run.failed\ndata: %s\n\n", err.Error())
+		flusher.Flush()
+		return
+	}
+
+	fmt.Fprintf(w, "event: thread.run.completed\ndata: done\n\n")
+	flusher.Flush()
 }
@@ -0,0 +1,86 @@
+// Package middleware provides HTTP middleware shared across handlers.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey string
+
+const userIDContextKey contextKey = "userID"
+
+// UnauthenticatedMode lets requests through without a valid token, keyed
+// under a fixed "default" user ID, so existing single-user deployments keep
+// working without JWT_SECRET configured. Toggle with the
+// UNAUTHENTICATED_MODE env var.
+var UnauthenticatedMode = os.Getenv("UNAUTHENTICATED_MODE") == "true"
+
+// defaultUserID is the user ID assigned to requests in UnauthenticatedMode.
+const defaultUserID = "default"
+
+// Authenticate parses a Bearer JWT (HS256, secret from JWT_SECRET) from the
+// Authorization header, extracts the "sub" claim as the user ID, and places
+// it in the request context for handlers to read with UserID. Wrap /ask and
+// /initialize with this.
+func Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := parseBearerToken(r)
+		if err != nil {
+			if UnauthenticatedMode {
+				next(w, withUserID(r, defaultUserID))
+				return
+			}
+			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+			return
+		}
+
+		next(w, withUserID(r, userID))
+	}
+}
+
+func withUserID(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userIDContextKey, userID))
+}
+
+func parseBearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", fmt.Errorf("missing bearer token")
+	}
+	tokenString := strings.TrimPrefix(header, "Bearer ")
+
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return "", fmt.Errorf("invalid token: %v", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", fmt.Errorf("invalid token claims")
+	}
+
+	sub, ok := claims["sub"].(string)
+	if !ok || sub == "" {
+		return "", fmt.Errorf("token missing sub claim")
+	}
+
+	return sub, nil
+}
+
+// UserID reads the authenticated user ID placed in the request context by
+// Authenticate. It returns "" if the request was never authenticated.
+func UserID(r *http.Request) string {
+	userID, _ := r.Context().Value(userIDContextKey).(string)
+	return userID
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const tokenTTL = 24 * time.Hour
+
+// RegisterUser hashes password with bcrypt and stores the account in Redis
+// under user:{email}.
+func RegisterUser(email, password string) error {
+	exists, err := RedisClient.Exists(Ctx, "user:"+email).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check for existing user: %v", err)
+	}
+	if exists > 0 {
+		return fmt.Errorf("a user with email %s already exists", email)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	if err := RedisClient.Set(Ctx, "user:"+email, string(hashed), 0).Err(); err != nil {
+		return fmt.Errorf("failed to store user: %v", err)
+	}
+
+	return nil
+}
+
+// LoginUser verifies password against the stored hash for email and, on
+// success, mints a JWT with the email as the "sub" claim.
+func LoginUser(email, password string) (string, error) {
+	hashed, err := RedisClient.Get(Ctx, "user:"+email).Result()
+	if err != nil {
+		return "", fmt.Errorf("invalid email or password")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)); err != nil {
+		return "", fmt.Errorf("invalid email or password")
+	}
+
+	return mintToken(email)
+}
+
+func mintToken(userID string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"exp": time.Now().Add(tokenTTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %v", err)
+	}
+
+	return signed, nil
+}
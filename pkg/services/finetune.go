@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ftModelKey is where a fine-tuned model ID is stored for a channel once a
+// fine-tune job trained on that channel's prior interactions succeeds.
+// CreateAssistantWithMetadata checks it before falling back to the client's
+// default model.
+func ftModelKey(channelID string) string {
+	return "ft_model:" + channelID
+}
+
+// SetFineTunedModel records modelID as the model to use for new assistants
+// created for channelID's videos.
+func SetFineTunedModel(channelID, modelID string) error {
+	return RedisClient.Set(Ctx, ftModelKey(channelID), modelID, 0).Err()
+}
+
+func ftJobChannelKey(jobID string) string {
+	return "ft_job_channel:" + jobID
+}
+
+// RecordFineTuneJobChannel remembers which channel a fine-tuning job was
+// trained for, so its model can be adopted once the job succeeds.
+func RecordFineTuneJobChannel(jobID, channelID string) error {
+	return RedisClient.Set(Ctx, ftJobChannelKey(jobID), channelID, 0).Err()
+}
+
+// FineTuneJobChannel looks up the channel recorded for jobID by
+// RecordFineTuneJobChannel.
+func FineTuneJobChannel(jobID string) (string, error) {
+	return RedisClient.Get(Ctx, ftJobChannelKey(jobID)).Result()
+}
+
+// trainingExample is one line of the JSONL fine-tuning file: a conversation
+// the assistant already had, in the chat-completions message format the
+// fine-tuning endpoint expects.
+type trainingExample struct {
+	Messages []openai.ChatCompletionMessage `json:"messages"`
+}
+
+// trainingExampleFromHistory converts a Redis interactions list (plain user
+// questions, assistant answers prefixed "Assistant: ") into a training
+// example.
+func trainingExampleFromHistory(history []string) (trainingExample, bool) {
+	if len(history) == 0 {
+		return trainingExample{}, false
+	}
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(history))
+	for _, entry := range history {
+		if strings.HasPrefix(entry, "Assistant: ") {
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: strings.TrimPrefix(entry, "Assistant: "),
+			})
+		} else {
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: entry,
+			})
+		}
+	}
+
+	return trainingExample{Messages: messages}, true
+}
+
+// BuildTrainingFile aggregates every user's interaction history for each of
+// videoIDs into a JSONL training file, uploads it with purpose=fine-tune,
+// and returns the resulting file ID.
+func BuildTrainingFile(videoIDs []string) (string, error) {
+	var buf bytes.Buffer
+
+	for _, videoID := range videoIDs {
+		keys, err := RedisClient.Keys(Ctx, interactionsKey("*", videoID)).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to list interaction keys for video %s: %v", videoID, err)
+		}
+
+		for _, key := range keys {
+			history, err := RedisClient.LRange(Ctx, key, 0, -1).Result()
+			if err != nil {
+				return "", fmt.Errorf("failed to read interactions for key %s: %v", key, err)
+			}
+
+			example, ok := trainingExampleFromHistory(history)
+			if !ok {
+				continue
+			}
+
+			line, err := json.Marshal(example)
+			if err != nil {
+				return "", fmt.Errorf("failed to marshal training example: %v", err)
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+
+	tmpFile, err := ioutil.TempFile("", "finetune-*.jsonl")
+	if err != nil {
+		return "", fmt.Errorf("failed to create training file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(buf.Bytes()); err != nil {
+		tmpFile.Close()
+		return "", fmt.Errorf("failed to write training file: %v", err)
+	}
+	tmpFile.Close()
+
+	file, err := DefaultClient.SDK.CreateFile(context.Background(), openai.FileRequest{
+		FileName: "training-data.jsonl",
+		FilePath: tmpFile.Name(),
+		Purpose:  "fine-tune",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload training file: %v", err)
+	}
+
+	return file.ID, nil
+}
+
+// CreateFineTuningJob kicks off a fine-tune of baseModel on trainingFileID.
+func CreateFineTuningJob(trainingFileID, baseModel string) (openai.FineTuningJob, error) {
+	return DefaultClient.SDK.CreateFineTuningJob(context.Background(), openai.FineTuningJobRequest{
+		TrainingFile: trainingFileID,
+		Model:        baseModel,
+	})
+}
+
+// RetrieveFineTuningJob looks up the status of a previously created job.
+func RetrieveFineTuningJob(jobID string) (openai.FineTuningJob, error) {
+	return DefaultClient.SDK.RetrieveFineTuningJob(context.Background(), jobID)
+}
+
+// CancelFineTuningJob stops a running fine-tune job.
+func CancelFineTuningJob(jobID string) (openai.FineTuningJob, error) {
+	return DefaultClient.SDK.CancelFineTuningJob(context.Background(), jobID)
+}
+
+// ListFineTuningJobEvents returns the training progress events for jobID.
+func ListFineTuningJobEvents(jobID string) (openai.FineTuningJobEventList, error) {
+	return DefaultClient.SDK.ListFineTuningJobEvents(context.Background(), jobID)
+}
@@ -1,21 +1,27 @@
 package services
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"os"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+
+	"Youtube-Learning-Mode-Ai-Service/pkg/backend"
+	"Youtube-Learning-Mode-Ai-Service/pkg/tools"
 )
 
 type ThreadManager struct {
 	ThreadID string
+	Client   *OpenAIClient
 }
 
 var (
@@ -23,104 +29,240 @@ var (
 	mutex          sync.Mutex
 )
 
+// threadKey and interactionsKey scope a video's thread/interaction history to
+// the user asking about it, so conversations no longer leak across users.
+func threadKey(userID, videoID string) string {
+	return fmt.Sprintf("thread_id:%s:%s", userID, videoID)
+}
+
+func interactionsKey(userID, videoID string) string {
+	return fmt.Sprintf("interactions:%s:%s", userID, videoID)
+}
+
+func threadManagerKey(userID, assistantID string) string {
+	return fmt.Sprintf("%s:%s", userID, assistantID)
+}
+
 // Define InitializeRequest in services.go
 type InitializeRequest struct {
-	SystemInstructions string `json:"system_instructions"`
-	VideoID            string `json:"video_id"`
-	Title              string `json:"title"`
-	Channel            string `json:"channel"`
-	Transcript         string `json:"transcript"`
+	SystemInstructions string              `json:"system_instructions"`
+	VideoID            string              `json:"video_id"`
+	Title              string              `json:"title"`
+	Channel            string              `json:"channel"`
+	Transcript         []TranscriptSegment `json:"transcript"`
 }
 
-// Initialize the OpenAI client and load the API key
-func InitOpenAIClient() {
-	if err := godotenv.Load("../.env"); err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
+// CreateGPTSession wires an InitRequest from the handler into an assistant
+// session: it creates a new assistant and indexes the transcript
+// for retrieval. transcript carries each segment's real Start/End so the
+// chunks indexed for retrieval cite real timestamps rather than approximated
+// ones.
+func CreateGPTSession(videoID, title, channel string, transcript []TranscriptSegment) error {
+	_, err := CreateAssistantWithMetadata(InitializeRequest{
+		VideoID:    videoID,
+		Title:      title,
+		Channel:    channel,
+		Transcript: transcript,
+	})
+	return err
+}
+
+// instructionsFor builds the short role prompt given to the assistant. It is
+// also stored in Redis so ChatCompletionsFallback can reuse it as the system
+// message for backends that have no assistant object to carry it.
+func instructionsFor(title, channel string) string {
+	return fmt.Sprintf("You are a helpful assistant for the video titled '%s' by '%s'. Answer questions using the relevant transcript excerpts provided to you in the conversation, and cite the timestamps they cover when useful.", title, channel)
+}
+
+func instructionsKey(videoID string) string {
+	return "instructions:" + videoID
 }
 
-// CreateAssistantWithMetadata creates a new assistant based on YouTube video metadata
+// CreateAssistantWithMetadata creates a new assistant based on YouTube video metadata.
+// The transcript is chunked and embedded for retrieval rather than stuffed into the
+// instructions, so the assistant instructions only carry a short role prompt. On
+// chat-completions-only backends there is no assistant object to create, so the
+// instructions are simply stored for later reuse as the system prompt and a
+// synthetic ID is returned.
+//
+// If a fine-tuned model was trained for initReq.Channel (see pkg/finetune), the
+// assistant is created on that model instead of the client's default.
 func CreateAssistantWithMetadata(initReq InitializeRequest) (string, error) {
-	url := "https://api.openai.com/v1/assistants"
+	instructions := instructionsFor(initReq.Title, initReq.Channel)
+	if err := RedisClient.Set(Ctx, instructionsKey(initReq.VideoID), instructions, 24*time.Hour).Err(); err != nil {
+		return "", fmt.Errorf("failed to store assistant instructions in Redis: %v", err)
+	}
 
-	requestBody := map[string]interface{}{
-		"model":        "gpt-4o-mini",
-		"name":         initReq.VideoID,
-		"instructions": fmt.Sprintf("You are a helpful assistant for the video titled '%s' by '%s'. Here is the transcript: %s", initReq.Title, initReq.Channel, initReq.Transcript),
+	model := DefaultClient.Model
+	if ftModel, err := RedisClient.Get(Ctx, ftModelKey(initReq.Channel)).Result(); err == nil && ftModel != "" {
+		model = ftModel
 	}
 
-	body, err := json.Marshal(requestBody)
+	var assistantID string
+	if backend.Current == backend.ChatCompletions {
+		assistantID = "chat:" + initReq.VideoID
+	} else {
+		id, err := DefaultClient.createAssistant(initReq.VideoID, instructions, model)
+		if err != nil {
+			return "", err
+		}
+		assistantID = id
+	}
+
+	// Store the assistant ID in Redis
+	err := RedisClient.Set(Ctx, "assistant_id:"+initReq.VideoID, assistantID, 24*time.Hour).Err()
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %v", err)
+		return "", fmt.Errorf("failed to store assistant ID in Redis: %v", err)
+	}
+
+	chunks := ChunkTranscript(initReq.VideoID, initReq.Transcript)
+	if err := IndexChunks(chunks); err != nil {
+		return "", fmt.Errorf("failed to index transcript chunks: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	return assistantID, nil
+}
+
+// createAssistant creates the assistant object itself via the Assistants API.
+func (c *OpenAIClient) createAssistant(videoID, instructions, model string) (string, error) {
+	sdkTools, err := sdkToolsFromDefinitions(tools.Definitions())
 	if err != nil {
-		return "", fmt.Errorf("failed to create HTTP request: %v", err)
+		return "", fmt.Errorf("failed to convert tool definitions: %v", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("OPENAI_API_KEY")))
-	req.Header.Set("OpenAI-Beta", "assistants=v2")
+	assistant, err := c.SDK.CreateAssistant(context.Background(), openai.AssistantRequest{
+		Model:        model,
+		Name:         &videoID,
+		Instructions: &instructions,
+		Tools:        sdkTools,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create assistant: %v", err)
+	}
+
+	return assistant.ID, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// AssistantAnswer is the assistant's final message together with the
+// transcript timestamps it cited via the search_transcript tool, so the
+// frontend can render clickable timestamps.
+type AssistantAnswer struct {
+	Response  string     `json:"response"`
+	Citations []Citation `json:"citations"`
+}
+
+// Citation is a transcript timestamp range the assistant drew on to answer.
+type Citation struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// AskAssistantQuestion adds a question to userID's thread for videoID and gets a response.
+func AskAssistantQuestion(userID, videoID, assistantID, question string) (*AssistantAnswer, error) {
+	if backend.Current == backend.ChatCompletions {
+		instructions, err := RedisClient.Get(Ctx, instructionsKey(videoID)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up assistant instructions for video %s: %v", videoID, err)
+		}
+		answer, err := DefaultClient.ChatCompletionsFallback(userID, videoID, instructions, question)
+		if err != nil {
+			return nil, err
+		}
+		return &AssistantAnswer{Response: answer}, nil
+	}
+
+	threadManager, err := GetOrCreateThreadManager(userID, videoID, assistantID)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		return nil, fmt.Errorf("failed to get thread manager: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to create assistant: %s", string(bodyBytes))
+	if err := injectRetrievedContext(threadManager, videoID, question); err != nil {
+		log.Printf("Failed to inject retrieved transcript context for video %s: %v", videoID, err)
 	}
 
-	var createResp struct {
-		ID string `json:"id"`
+	err = threadManager.AddMessageToThread("user", question, userID, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add message: %v", err)
 	}
-	err = json.NewDecoder(resp.Body).Decode(&createResp)
+
+	return threadManager.RunAssistant(assistantID, userID, videoID)
+}
+
+// injectRetrievedContext adds the top transcript chunks relevant to question
+// as a message ahead of the real question. The Assistants API only supports
+// "user"/"assistant" roles on thread messages, so context is injected as a
+// user message rather than a true system message. It's added to the thread
+// only, not recorded in the interactions list: that list is replayed as chat
+// history by ChatCompletionsFallback and turned into fine-tuning examples by
+// BuildTrainingFile, and these excerpts are retrieval context rather than
+// something either a user or a trained model should learn to reproduce.
+func injectRetrievedContext(tm *ThreadManager, videoID, question string) error {
+	chunks, err := SearchChunks(videoID, question, TopKChunks)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
+		return fmt.Errorf("failed to search transcript chunks: %v", err)
+	}
+	if len(chunks) == 0 {
+		return nil
 	}
 
-	// Store the assistant ID in Redis
-	err = RedisClient.Set(Ctx, "assistant_id:"+initReq.VideoID, createResp.ID, 24*time.Hour).Err()
+	var context strings.Builder
+	context.WriteString("Relevant transcript excerpts for the next question:\n")
+	for _, chunk := range chunks {
+		fmt.Fprintf(&context, "[%.0fs-%.0fs] %s\n", chunk.Start, chunk.End, chunk.Text)
+	}
+
+	return tm.addThreadMessage("user", context.String())
+}
+
+// FetchGPTResponse looks up the assistant already created for videoID and runs
+// userID's question through the blocking (polling) path.
+func FetchGPTResponse(userID, videoID, question string) (*AssistantAnswer, error) {
+	assistantID, err := RedisClient.Get(Ctx, "assistant_id:"+videoID).Result()
 	if err != nil {
-		return "", fmt.Errorf("failed to store assistant ID in Redis: %v", err)
+		return nil, fmt.Errorf("failed to look up assistant for video %s: %v", videoID, err)
 	}
 
-	return createResp.ID, nil
+	return AskAssistantQuestion(userID, videoID, assistantID, question)
 }
 
-// AskAssistantQuestion adds a question to the thread and gets a response
-func AskAssistantQuestion(videoID, assistantID, question string) (string, error) {
-	threadManager, err := GetOrCreateThreadManager(videoID, assistantID)
+// AskAssistantQuestionStream is the streaming counterpart to AskAssistantQuestion.
+// onDelta is invoked with each text fragment as it arrives from the run.
+func AskAssistantQuestionStream(userID, videoID, assistantID, question string, onDelta func(string)) (string, error) {
+	threadManager, err := GetOrCreateThreadManager(userID, videoID, assistantID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get thread manager: %v", err)
 	}
 
-	err = threadManager.AddMessageToThread("user", question, videoID)
+	if err := injectRetrievedContext(threadManager, videoID, question); err != nil {
+		log.Printf("Failed to inject retrieved transcript context for video %s: %v", videoID, err)
+	}
+
+	err = threadManager.AddMessageToThread("user", question, userID, videoID)
 	if err != nil {
 		return "", fmt.Errorf("failed to add message: %v", err)
 	}
 
-	return threadManager.RunAssistant(assistantID, videoID)
+	return threadManager.RunAssistantStream(assistantID, userID, videoID, onDelta)
 }
 
-// GetOrCreateThreadManager retrieves the thread from Redis or creates a new one if it doesn't exist
-func GetOrCreateThreadManager(videoID string, assistantID string) (*ThreadManager, error) {
+// GetOrCreateThreadManager retrieves userID's thread for videoID from Redis,
+// or creates a new one if it doesn't exist. Scoping by userID keeps each
+// user's conversation with a video separate from every other user's.
+func GetOrCreateThreadManager(userID, videoID, assistantID string) (*ThreadManager, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
+	key := threadKey(userID, videoID)
+
 	// Check if a thread ID already exists in Redis
-	threadID, err := RedisClient.Get(Ctx, "thread_id:"+videoID).Result()
+	threadID, err := RedisClient.Get(Ctx, key).Result()
 	if err != nil {
 		fmt.Printf("Error type: %T\n", err) // Print the type of the error
 		if err.Error() == "redis: nil" {
 			log.Println("Redis key not found for videoID:", videoID)
 
 			// Create a new thread if no thread exists
-			threadID, err = createThread()
+			threadID, err = DefaultClient.createThread()
 			if err != nil {
 				return nil, fmt.Errorf("failed to create thread: %v", err)
 			}
@@ -129,7 +271,7 @@ func GetOrCreateThreadManager(videoID string, assistantID string) (*ThreadManage
 			log.Printf("New thread created with ID: %s for video: %s", threadID, videoID)
 
 			// Store the new thread ID in Redis
-			err = RedisClient.Set(Ctx, "thread_id:"+videoID, threadID, 24*time.Hour).Err()
+			err = RedisClient.Set(Ctx, key, threadID, 24*time.Hour).Err()
 			if err != nil {
 				log.Printf("Failed to store thread ID in Redis for video %s: %v", videoID, err)
 				return nil, fmt.Errorf("failed to store thread ID in Redis: %v", err)
@@ -145,102 +287,30 @@ func GetOrCreateThreadManager(videoID string, assistantID string) (*ThreadManage
 	}
 
 	// Create a ThreadManager instance
-	tm := &ThreadManager{ThreadID: threadID}
-	threadManagers[assistantID] = tm
+	tm := &ThreadManager{ThreadID: threadID, Client: DefaultClient}
+	threadManagers[threadManagerKey(userID, assistantID)] = tm
 	return tm, nil
 }
 
-func createThread() (string, error) {
-	url := "https://api.openai.com/v1/threads"
-	requestBody := map[string]interface{}{}
-
-	body, err := json.Marshal(requestBody)
+func (c *OpenAIClient) createThread() (string, error) {
+	thread, err := c.SDK.CreateThread(context.Background(), openai.ThreadRequest{})
 	if err != nil {
-		log.Printf("Failed to marshal thread creation request: %v", err)
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		log.Printf("Failed to create thread: %v", err)
+		return "", fmt.Errorf("failed to create thread: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		log.Printf("Failed to create HTTP request for thread creation: %v", err)
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("OPENAI_API_KEY")))
-	req.Header.Set("OpenAI-Beta", "assistants=v2")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Failed to send thread creation request: %v", err)
-		return "", fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("Thread creation failed with status code %d: %s", resp.StatusCode, string(bodyBytes))
-		return "", fmt.Errorf("failed to create thread: %s", string(bodyBytes))
-	}
-
-	var threadResp struct {
-		ID string `json:"id"`
-	}
-	err = json.NewDecoder(resp.Body).Decode(&threadResp)
-	if err != nil {
-		log.Printf("Failed to decode thread creation response: %v", err)
-		return "", fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	log.Printf("Thread created with ID %s", threadResp.ID)
-	return threadResp.ID, nil
+	log.Printf("Thread created with ID %s", thread.ID)
+	return thread.ID, nil
 }
 
 // Storing each interaction message in Redis
-func (tm *ThreadManager) AddMessageToThread(role, content, videoID string) error {
-	url := fmt.Sprintf("https://api.openai.com/v1/threads/%s/messages", tm.ThreadID)
-
-	// Log the message being added
-	log.Printf("Adding message to thread. Role: %s, Content: %s, VideoID: %s", role, content, videoID)
-
-	requestBody := map[string]interface{}{
-		"role":    role,
-		"content": content,
-	}
-
-	body, err := json.Marshal(requestBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("OPENAI_API_KEY")))
-	req.Header.Set("OpenAI-Beta", "assistants=v2")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("Failed to add message to thread. StatusCode: %d, Response: %s", resp.StatusCode, string(bodyBytes))
-		return fmt.Errorf("failed to add message to thread: %s", string(bodyBytes))
+func (tm *ThreadManager) AddMessageToThread(role, content, userID, videoID string) error {
+	if err := tm.addThreadMessage(role, content); err != nil {
+		return err
 	}
 
-	// Log success in adding message to thread
-	log.Printf("Message added to thread. Role: %s, Content: %s, VideoID: %s", role, content, videoID)
-
-	// Store the interaction message in Redis under the videoID key
-	err = RedisClient.RPush(Ctx, "interactions:"+videoID, content).Err()
+	// Store the interaction message in Redis under the user+videoID key
+	err := RedisClient.RPush(Ctx, interactionsKey(userID, videoID), content).Err()
 	if err != nil {
 		log.Printf("Failed to store interaction in Redis for VideoID %s: %v", videoID, err)
 		return fmt.Errorf("failed to store interaction in Redis: %v", err)
@@ -251,60 +321,52 @@ func (tm *ThreadManager) AddMessageToThread(role, content, videoID string) error
 	return nil
 }
 
-func (tm *ThreadManager) RunAssistant(assistantID string, videoID string) (string, error) {
-	url := fmt.Sprintf("https://api.openai.com/v1/threads/%s/runs", tm.ThreadID)
+// addThreadMessage adds a message to the OpenAI thread only, without
+// recording it as an interaction in Redis. Use this for content that isn't
+// an actual user/assistant turn, such as injected retrieval context.
+func (tm *ThreadManager) addThreadMessage(role, content string) error {
+	log.Printf("Adding message to thread. Role: %s, Content: %s, ThreadID: %s", role, content, tm.ThreadID)
 
-	requestBody := map[string]interface{}{
-		"assistant_id": assistantID,
-	}
-
-	body, err := json.Marshal(requestBody)
+	_, err := tm.Client.SDK.CreateMessage(context.Background(), tm.ThreadID, openai.MessageRequest{
+		Role:    role,
+		Content: content,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return "", fmt.Errorf("failed to create HTTP request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("OPENAI_API_KEY")))
-	req.Header.Set("OpenAI-Beta", "assistants=v2")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		log.Printf("Failed to add message to thread. Role: %s, ThreadID: %s, Error: %v", role, tm.ThreadID, err)
+		return fmt.Errorf("failed to add message to thread: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to run assistant: %s", string(bodyBytes))
-	}
+	log.Printf("Message added to thread. Role: %s, Content: %s, ThreadID: %s", role, content, tm.ThreadID)
+	return nil
+}
 
-	var runResp struct {
-		ID     string `json:"id"`
-		Status string `json:"status"`
-	}
-	err = json.NewDecoder(resp.Body).Decode(&runResp)
+func (tm *ThreadManager) RunAssistant(assistantID, userID, videoID string) (*AssistantAnswer, error) {
+	run, err := tm.Client.SDK.CreateRun(context.Background(), tm.ThreadID, openai.RunRequest{AssistantID: assistantID})
 	if err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
+		return nil, fmt.Errorf("failed to run assistant: %v", err)
 	}
 
-	// Poll for completion
+	// Poll for completion, dispatching any tool calls the model requests along the way.
+	var citations []Citation
 	for {
 		time.Sleep(2 * time.Second)
-		status, err := tm.GetRunStatus(runResp.ID)
+		run, err = tm.Client.SDK.RetrieveRun(context.Background(), tm.ThreadID, run.ID)
 		if err != nil {
-			return "", fmt.Errorf("failed to get run status: %v", err)
+			return nil, fmt.Errorf("failed to get run status: %v", err)
 		}
 
-		if status == "completed" {
+		switch run.Status {
+		case openai.RunStatusRequiresAction:
+			newCitations, err := tm.handleRequiredAction(run, videoID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to handle tool calls: %v", err)
+			}
+			citations = append(citations, newCitations...)
+
+		case openai.RunStatusCompleted:
 			messages, err := tm.GetThreadMessages()
 			if err != nil {
-				return "", fmt.Errorf("failed to get thread messages: %v", err)
+				return nil, fmt.Errorf("failed to get thread messages: %v", err)
 			}
 
 			// Return the assistant message
@@ -317,105 +379,226 @@ func (tm *ThreadManager) RunAssistant(assistantID string, videoID string) (strin
 						}
 					}
 					// Store assistant's response in Redis
-					err = RedisClient.RPush(Ctx, "interactions:"+videoID, "Assistant: "+assistantResponse).Err()
+					err = RedisClient.RPush(Ctx, interactionsKey(userID, videoID), "Assistant: "+assistantResponse).Err()
 					if err != nil {
 						log.Printf("Failed to store assistant response in Redis for ThreadID %s: %v", tm.ThreadID, err)
-						return "", fmt.Errorf("failed to store assistant response in Redis: %v", err)
+						return nil, fmt.Errorf("failed to store assistant response in Redis: %v", err)
 					}
 
 					log.Printf("Assistant response stored in Redis for ThreadID: %s", tm.ThreadID)
-					return assistantResponse, nil
+					return &AssistantAnswer{Response: assistantResponse, Citations: citations}, nil
 				}
 			}
-			return "", fmt.Errorf("no assistant message found")
+			return nil, fmt.Errorf("no assistant message found")
+
+		case openai.RunStatusFailed, openai.RunStatusCancelled, openai.RunStatusExpired:
+			return nil, fmt.Errorf("run ended with status: %s", run.Status)
 		}
 	}
 }
 
-func (tm *ThreadManager) GetRunStatus(runID string) (string, error) {
-	url := fmt.Sprintf("https://api.openai.com/v1/threads/%s/runs/%s", tm.ThreadID, runID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create HTTP request: %v", err)
+// handleRequiredAction dispatches every pending tool call to the tools
+// package, submits their outputs, and returns any citations surfaced by
+// search_transcript calls.
+func (tm *ThreadManager) handleRequiredAction(run openai.Run, videoID string) ([]Citation, error) {
+	if run.RequiredAction == nil {
+		return nil, fmt.Errorf("requires_action status with no required_action payload")
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("OPENAI_API_KEY")))
-	req.Header.Set("OpenAI-Beta", "assistants=v2")
+	var citations []Citation
+	toolCalls := run.RequiredAction.SubmitToolOutputs.ToolCalls
+	outputs := make([]openai.ToolOutput, 0, len(toolCalls))
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+	for _, call := range toolCalls {
+		result, err := tools.Dispatch(call.Function.Name, videoID, call.Function.Arguments)
+		if err != nil {
+			log.Printf("Tool %s failed for video %s: %v", call.Function.Name, videoID, err)
+			result = fmt.Sprintf(`{"error": %q}`, err.Error())
+		} else if call.Function.Name == "search_transcript" {
+			citations = append(citations, citationsFromToolOutput(result)...)
+		}
+
+		outputs = append(outputs, openai.ToolOutput{ToolCallID: call.ID, Output: result})
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to get run status: %s", string(bodyBytes))
+	if _, err := tm.Client.SDK.SubmitToolOutputs(context.Background(), tm.ThreadID, run.ID, openai.SubmitToolOutputsRequest{ToolOutputs: outputs}); err != nil {
+		return nil, fmt.Errorf("failed to submit tool outputs: %v", err)
 	}
 
-	var runStatus struct {
-		Status string `json:"status"`
+	return citations, nil
+}
+
+func citationsFromToolOutput(output string) []Citation {
+	var matches []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
 	}
-	err = json.NewDecoder(resp.Body).Decode(&runStatus)
-	if err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
+	if err := json.Unmarshal([]byte(output), &matches); err != nil {
+		return nil
 	}
 
-	return runStatus.Status, nil
+	citations := make([]Citation, len(matches))
+	for i, match := range matches {
+		citations[i] = Citation{Start: match.Start, End: match.End}
+	}
+	return citations
 }
 
-func (tm *ThreadManager) GetThreadMessages() ([]Message, error) {
-	url := fmt.Sprintf("https://api.openai.com/v1/threads/%s/messages", tm.ThreadID)
+// RunAssistantStream opens the run with "stream": true and parses the
+// text/event-stream body as it arrives, calling onDelta for every
+// thread.message.delta fragment. The full assistant answer is only written to
+// Redis once a terminal event (thread.run.completed) is received.
+//
+// go-openai has no streaming support for Assistants runs, so this path keeps
+// talking to the API directly rather than going through OpenAIClient.SDK.
+func (tm *ThreadManager) RunAssistantStream(assistantID, userID, videoID string, onDelta func(string)) (string, error) {
+	return tm.streamRun(
+		fmt.Sprintf("/threads/%s/runs", tm.ThreadID),
+		map[string]interface{}{"assistant_id": assistantID, "stream": true},
+		userID, videoID, onDelta,
+	)
+}
 
-	// Log the retrieval request
-	log.Printf("Fetching messages from thread with ID: %s", tm.ThreadID)
+// streamRunRequiresAction mirrors the subset of a streamed run object this
+// package needs to decode a thread.run.requires_action event.
+type streamRunRequiresAction struct {
+	ID             string `json:"id"`
+	RequiredAction *struct {
+		SubmitToolOutputs struct {
+			ToolCalls []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"submit_tool_outputs"`
+	} `json:"required_action"`
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// streamRun POSTs requestBody to path and consumes the resulting
+// text/event-stream. On thread.run.requires_action it dispatches the
+// requested tool calls and resumes the stream via submit_tool_outputs, the
+// same way RunAssistant's polling path does for non-streamed runs.
+func (tm *ThreadManager) streamRun(path string, requestBody map[string]interface{}, userID, videoID string, onDelta func(string)) (string, error) {
+	body, err := json.Marshal(requestBody)
 	if err != nil {
-		log.Printf("Failed to create HTTP request for thread message retrieval: %v", err)
-		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+		return "", fmt.Errorf("failed to marshal request body: %v", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", os.Getenv("OPENAI_API_KEY")))
+	req, err := http.NewRequest("POST", tm.Client.url(path), bytes.NewBuffer(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+
+	tm.Client.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
 	req.Header.Set("OpenAI-Beta", "assistants=v2")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := tm.Client.HTTPClient.Do(req)
 	if err != nil {
-		log.Printf("Failed to send request to get thread messages: %v", err)
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return "", fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("Failed to fetch thread messages. StatusCode: %d, Response: %s", resp.StatusCode, string(bodyBytes))
-		return nil, fmt.Errorf("failed to get thread messages: %s", string(bodyBytes))
+		return "", fmt.Errorf("failed to run assistant: %s", string(bodyBytes))
 	}
 
-	// Log the raw response body from OpenAI for debugging purposes
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Failed to read response body: %v", err)
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
+	var assistantResponse strings.Builder
+	var currentEvent string
 
-	log.Printf("Raw thread messages response: %s", string(bodyBytes))
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
-	var messagesResp struct {
-		Data []Message `json:"data"`
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			currentEvent = strings.TrimPrefix(line, "event: ")
+
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+
+			switch currentEvent {
+			case "thread.message.delta":
+				var deltaEvent messageDeltaEvent
+				if err := json.Unmarshal([]byte(data), &deltaEvent); err != nil {
+					log.Printf("Failed to decode message delta event: %v", err)
+					continue
+				}
+				for _, fragment := range deltaEvent.Delta.Content {
+					if fragment.Type == "text" && fragment.Text != nil {
+						assistantResponse.WriteString(fragment.Text.Value)
+						if onDelta != nil {
+							onDelta(fragment.Text.Value)
+						}
+					}
+				}
+
+			case "thread.run.completed":
+				finalResponse := assistantResponse.String()
+				if err := RedisClient.RPush(Ctx, interactionsKey(userID, videoID), "Assistant: "+finalResponse).Err(); err != nil {
+					log.Printf("Failed to store assistant response in Redis for ThreadID %s: %v", tm.ThreadID, err)
+					return "", fmt.Errorf("failed to store assistant response in Redis: %v", err)
+				}
+				log.Printf("Assistant response stored in Redis for ThreadID: %s", tm.ThreadID)
+				return finalResponse, nil
+
+			case "thread.run.requires_action":
+				var run streamRunRequiresAction
+				if err := json.Unmarshal([]byte(data), &run); err != nil {
+					return "", fmt.Errorf("failed to decode requires_action event: %v", err)
+				}
+				if run.RequiredAction == nil {
+					return "", fmt.Errorf("requires_action event with no required_action payload")
+				}
+
+				toolCalls := run.RequiredAction.SubmitToolOutputs.ToolCalls
+				outputs := make([]map[string]string, 0, len(toolCalls))
+				for _, call := range toolCalls {
+					result, err := tools.Dispatch(call.Function.Name, videoID, call.Function.Arguments)
+					if err != nil {
+						log.Printf("Tool %s failed for video %s: %v", call.Function.Name, videoID, err)
+						result = fmt.Sprintf(`{"error": %q}`, err.Error())
+					}
+					outputs = append(outputs, map[string]string{"tool_call_id": call.ID, "output": result})
+				}
+
+				// The SSE response ends once requires_action is emitted, so
+				// resume it by submitting the tool outputs on a fresh stream.
+				return tm.streamRun(
+					fmt.Sprintf("/threads/%s/runs/%s/submit_tool_outputs", tm.ThreadID, run.ID),
+					map[string]interface{}{"tool_outputs": outputs, "stream": true},
+					userID, videoID, onDelta,
+				)
+
+			case "thread.run.failed":
+				return "", fmt.Errorf("assistant run failed: %s", data)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read event stream: %v", err)
 	}
-	err = json.Unmarshal(bodyBytes, &messagesResp)
+
+	return "", fmt.Errorf("event stream ended before a terminal event was received")
+}
+
+func (tm *ThreadManager) GetThreadMessages() ([]openai.Message, error) {
+	log.Printf("Fetching messages from thread with ID: %s", tm.ThreadID)
+
+	resp, err := tm.Client.SDK.ListMessage(context.Background(), tm.ThreadID, nil, nil, nil, nil, nil)
 	if err != nil {
-		log.Printf("Failed to decode thread messages response: %v", err)
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+		log.Printf("Failed to fetch thread messages for ThreadID %s: %v", tm.ThreadID, err)
+		return nil, fmt.Errorf("failed to get thread messages: %v", err)
 	}
 
-	// Log successful message retrieval
-	log.Printf("Successfully fetched %d messages from thread with ID: %s", len(messagesResp.Data), tm.ThreadID)
-	return messagesResp.Data, nil
+	log.Printf("Successfully fetched %d messages from thread with ID: %s", len(resp.Messages), tm.ThreadID)
+	return resp.Messages, nil
 }
 
 type TextContent struct {
@@ -429,8 +612,9 @@ type ContentFragment struct {
 	// You can include other content types here like image, video, etc.
 }
 
-type Message struct {
-	ID      string            `json:"id"`
-	Role    string            `json:"role"`
-	Content []ContentFragment `json:"content"` // Content is now a list of fragments
+// messageDeltaEvent mirrors the payload of a thread.message.delta SSE event.
+type messageDeltaEvent struct {
+	Delta struct {
+		Content []ContentFragment `json:"content"`
+	} `json:"delta"`
 }
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// OpenAIClient holds everything needed to talk to an OpenAI-compatible API,
+// so the backend (OpenAI itself, Azure OpenAI, LocalAI, Ollama, ...) is a
+// matter of configuration rather than a hardcoded host. Most calls go
+// through SDK; BaseURL/APIKey/HTTPClient remain for the raw requests made
+// outside it (streaming assistant runs, embeddings, Whisper transcription).
+type OpenAIClient struct {
+	SDK        *openai.Client
+	Model      string
+	BaseURL    string
+	APIKey     string
+	OrgID      string
+	HTTPClient *http.Client
+}
+
+// DefaultClient is the client used by the package-level convenience
+// functions (CreateGPTSession, AskAssistantQuestion, ...) so existing
+// callers don't need to thread a client through.
+var DefaultClient *OpenAIClient
+
+// InitOpenAIClient loads .env and builds DefaultClient from the environment.
+func InitOpenAIClient() {
+	if err := godotenv.Load("../.env"); err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	DefaultClient = NewOpenAIClient()
+}
+
+// NewOpenAIClient builds an OpenAIClient from OPENAI_BASE_URL (defaulting to
+// the real OpenAI API), OPENAI_API_KEY, OPENAI_MODEL (defaulting to
+// gpt-4o-mini), and OPENAI_ORG.
+func NewOpenAIClient() *OpenAIClient {
+	baseURL := strings.TrimSuffix(os.Getenv("OPENAI_BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	orgID := os.Getenv("OPENAI_ORG")
+
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	if orgID != "" {
+		config.OrgID = orgID
+	}
+
+	return &OpenAIClient{
+		SDK:        openai.NewClientWithConfig(config),
+		Model:      model,
+		BaseURL:    baseURL,
+		APIKey:     apiKey,
+		OrgID:      orgID,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// url joins the client's base URL with a path such as "/threads". It is used
+// by the raw requests made outside SDK: streaming assistant runs, embeddings
+// and audio transcriptions.
+func (c *OpenAIClient) url(path string) string {
+	return c.BaseURL + path
+}
+
+// setHeaders applies the auth headers common to every raw request made
+// outside SDK.
+func (c *OpenAIClient) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+	if c.OrgID != "" {
+		req.Header.Set("OpenAI-Organization", c.OrgID)
+	}
+}
+
+// sdkToolsFromDefinitions adapts tools.Definitions()'s raw JSON-schema maps
+// (shared with the requires_action tool-call path) into the typed tool
+// list openai.AssistantRequest expects.
+func sdkToolsFromDefinitions(defs []map[string]interface{}) ([]openai.AssistantTool, error) {
+	raw, err := json.Marshal(defs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool definitions: %v", err)
+	}
+
+	var sdkTools []openai.AssistantTool
+	if err := json.Unmarshal(raw, &sdkTools); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tool definitions: %v", err)
+	}
+
+	return sdkTools, nil
+}
+
+// ChatCompletionsFallback emulates AskAssistantQuestion for backends that
+// don't implement the Assistants API (LocalAI, Ollama, ...): it replays
+// userID's interaction history for videoID as chat messages and calls
+// CreateChatCompletion instead of threads/runs.
+func (c *OpenAIClient) ChatCompletionsFallback(userID, videoID, systemPrompt, question string) (string, error) {
+	history, err := RedisClient.LRange(Ctx, interactionsKey(userID, videoID), 0, -1).Result()
+	if err != nil && err.Error() != "redis: nil" {
+		return "", fmt.Errorf("failed to read interaction history: %v", err)
+	}
+
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: systemPrompt}}
+	for _, entry := range history {
+		if strings.HasPrefix(entry, "Assistant: ") {
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: strings.TrimPrefix(entry, "Assistant: "),
+			})
+		} else {
+			messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: entry})
+		}
+	}
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: question})
+
+	resp, err := c.SDK.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    c.Model,
+		Messages: messages,
+	})
+	if err != nil {
+		return "", fmt.Errorf("chat completion failed: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("chat completion returned no choices")
+	}
+	answer := resp.Choices[0].Message.Content
+
+	if err := RedisClient.RPush(Ctx, interactionsKey(userID, videoID), question).Err(); err != nil {
+		log.Printf("Failed to store question in Redis for video %s: %v", videoID, err)
+	}
+	if err := RedisClient.RPush(Ctx, interactionsKey(userID, videoID), "Assistant: "+answer).Err(); err != nil {
+		log.Printf("Failed to store assistant response in Redis for video %s: %v", videoID, err)
+	}
+
+	return answer, nil
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	chunkWindowSeconds  = 45.0 // target window size, within the 30-60s range
+	chunkOverlapSeconds = 10.0
+
+	embeddingModel      = "text-embedding-3-small"
+	embeddingDimensions = 1536
+
+	vectorIndexName = "idx:chunks"
+
+	// TopKChunks is the number of transcript chunks retrieved per question.
+	TopKChunks = 5
+)
+
+// TranscriptChunk is a window of a video transcript together with the
+// approximate start/end timestamps it covers.
+type TranscriptChunk struct {
+	VideoID string
+	Start   float64
+	End     float64
+	Text    string
+}
+
+// ChunkTranscript splits a transcript into ~30-60 second overlapping windows,
+// sliding by actual segment timestamps rather than a fixed segment count so
+// the window boundaries line up with real time regardless of how densely
+// segments are spaced.
+func ChunkTranscript(videoID string, segments []TranscriptSegment) []TranscriptChunk {
+	var chunks []TranscriptChunk
+
+	for i := 0; i < len(segments); {
+		windowStart := segments[i].Start
+
+		var texts []string
+		windowEnd := windowStart
+		j := i
+		for j < len(segments) && segments[j].Start-windowStart < chunkWindowSeconds {
+			texts = append(texts, segments[j].Text)
+			windowEnd = segments[j].End
+			j++
+		}
+
+		chunks = append(chunks, TranscriptChunk{
+			VideoID: videoID,
+			Start:   windowStart,
+			End:     windowEnd,
+			Text:    strings.Join(texts, " "),
+		})
+
+		// This window already reaches the last segment; any later window
+		// would only repeat its tail.
+		if j == len(segments) {
+			break
+		}
+
+		// Advance to the first segment within chunkOverlapSeconds of this
+		// window's end, so the next window overlaps it by roughly that much.
+		next := i + 1
+		for next < j && segments[next].Start < windowEnd-chunkOverlapSeconds {
+			next++
+		}
+		i = next
+	}
+
+	return chunks
+}
+
+// IndexChunks embeds each chunk and persists it in Redis under
+// chunk:{videoID}:{index} so it can later be retrieved with SearchChunks.
+// Since chunks are keyed by position, any chunks already indexed for the
+// video are cleared first so re-initializing a video with a shorter
+// transcript doesn't leave stale higher-index chunks behind. Each chunk
+// expires after transcriptCacheTTL, the same as the transcript it was built
+// from.
+func IndexChunks(chunks []TranscriptChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	ensureVectorIndex()
+
+	videoID := chunks[0].VideoID
+	oldKeys, err := RedisClient.Keys(Ctx, fmt.Sprintf("chunk:%s:*", videoID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list existing chunks for video %s: %v", videoID, err)
+	}
+	if len(oldKeys) > 0 {
+		if err := RedisClient.Del(Ctx, oldKeys...).Err(); err != nil {
+			return fmt.Errorf("failed to clear existing chunks for video %s: %v", videoID, err)
+		}
+	}
+
+	for i, chunk := range chunks {
+		vec, err := embedText(chunk.Text)
+		if err != nil {
+			return fmt.Errorf("failed to embed chunk %d for video %s: %v", i, chunk.VideoID, err)
+		}
+
+		key := fmt.Sprintf("chunk:%s:%d", chunk.VideoID, i)
+		err = RedisClient.HSet(Ctx, key, map[string]interface{}{
+			"text":     chunk.Text,
+			"video_id": chunk.VideoID,
+			"start":    chunk.Start,
+			"end":      chunk.End,
+			"vec":      encodeVector(vec),
+		}).Err()
+		if err != nil {
+			return fmt.Errorf("failed to store chunk %d for video %s: %v", i, chunk.VideoID, err)
+		}
+		if err := RedisClient.Expire(Ctx, key, transcriptCacheTTL).Err(); err != nil {
+			return fmt.Errorf("failed to set expiry for chunk %d for video %s: %v", i, chunk.VideoID, err)
+		}
+	}
+
+	return nil
+}
+
+// SearchChunks embeds the question and returns the top-k most similar
+// transcript chunks for videoID, preferring a RediSearch KNN query and
+// falling back to an in-process cosine scan if RediSearch isn't available or
+// returns no matches (e.g. the index isn't loaded, or hasn't caught up with a
+// just-written chunk).
+func SearchChunks(videoID, question string, k int) ([]TranscriptChunk, error) {
+	queryVec, err := embedText(question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed question: %v", err)
+	}
+
+	chunks, err := searchChunksRediSearch(videoID, queryVec, k)
+	if err == nil && len(chunks) > 0 {
+		return chunks, nil
+	}
+	if err != nil {
+		log.Printf("RediSearch KNN query unavailable, falling back to in-process cosine search: %v", err)
+	}
+
+	return searchChunksNaive(videoID, queryVec, k)
+}
+
+var vectorIndexEnsured bool
+
+// ensureVectorIndex creates the RediSearch HNSW index on first use. It is a
+// no-op (besides logging) if RediSearch isn't loaded into Redis, since
+// SearchChunks falls back to a naive scan in that case.
+func ensureVectorIndex() {
+	if vectorIndexEnsured {
+		return
+	}
+	vectorIndexEnsured = true
+
+	_, err := RedisClient.Do(Ctx,
+		"FT.CREATE", vectorIndexName,
+		"ON", "HASH",
+		"PREFIX", "1", "chunk:",
+		"SCHEMA",
+		"text", "TEXT",
+		"video_id", "TAG",
+		"start", "NUMERIC",
+		"end", "NUMERIC",
+		"vec", "VECTOR", "HNSW", "6",
+		"TYPE", "FLOAT32",
+		"DIM", strconv.Itoa(embeddingDimensions),
+		"DISTANCE_METRIC", "COSINE",
+	).Result()
+	if err != nil && !strings.Contains(err.Error(), "Index already exists") {
+		log.Printf("RediSearch index unavailable, SearchChunks will fall back to in-process cosine search: %v", err)
+	}
+}
+
+// tagSpecialChars are the characters RediSearch treats as special inside a
+// TAG field value; YouTube video IDs routinely contain "-", which must be
+// backslash-escaped or the tag query matches nothing.
+const tagSpecialChars = ",.<>{}[]\"':;!@#$%^&*()-+=~| "
+
+func escapeTagValue(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(tagSpecialChars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func searchChunksRediSearch(videoID string, queryVec []float32, k int) ([]TranscriptChunk, error) {
+	query := fmt.Sprintf("(@video_id:{%s})=>[KNN %d @vec $vec AS score]", escapeTagValue(videoID), k)
+
+	res, err := RedisClient.Do(Ctx,
+		"FT.SEARCH", vectorIndexName, query,
+		"PARAMS", "2", "vec", encodeVector(queryVec),
+		"SORTBY", "score",
+		"DIALECT", "2",
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := res.([]interface{})
+	if !ok || len(rows) == 0 {
+		return nil, fmt.Errorf("unexpected FT.SEARCH response shape")
+	}
+
+	var chunks []TranscriptChunk
+	// rows[0] is the total match count; remaining entries alternate (key, fields).
+	for i := 1; i+1 < len(rows); i += 2 {
+		fields, ok := rows[i+1].([]interface{})
+		if !ok {
+			continue
+		}
+		chunks = append(chunks, chunkFromFTFields(videoID, fields))
+	}
+
+	return chunks, nil
+}
+
+func chunkFromFTFields(videoID string, fields []interface{}) TranscriptChunk {
+	chunk := TranscriptChunk{VideoID: videoID}
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, _ := fields[i].(string)
+		switch key {
+		case "text":
+			chunk.Text, _ = fields[i+1].(string)
+		case "start":
+			chunk.Start = toFloat(fields[i+1])
+		case "end":
+			chunk.End = toFloat(fields[i+1])
+		}
+	}
+	return chunk
+}
+
+// ListChunks returns every indexed transcript chunk for a video, ordered by
+// start timestamp. Unlike SearchChunks it performs no similarity ranking, so
+// it's suited to tools that need the whole transcript outline (e.g. chapters).
+func ListChunks(videoID string) ([]TranscriptChunk, error) {
+	keys, err := RedisClient.Keys(Ctx, fmt.Sprintf("chunk:%s:*", videoID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks for video %s: %v", videoID, err)
+	}
+
+	chunks := make([]TranscriptChunk, 0, len(keys))
+	for _, key := range keys {
+		fields, err := RedisClient.HGetAll(Ctx, key).Result()
+		if err != nil {
+			log.Printf("Failed to read chunk %s: %v", key, err)
+			continue
+		}
+
+		start, _ := strconv.ParseFloat(fields["start"], 64)
+		end, _ := strconv.ParseFloat(fields["end"], 64)
+		chunks = append(chunks, TranscriptChunk{VideoID: videoID, Start: start, End: end, Text: fields["text"]})
+	}
+
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Start < chunks[j].Start })
+
+	return chunks, nil
+}
+
+func searchChunksNaive(videoID string, queryVec []float32, k int) ([]TranscriptChunk, error) {
+	keys, err := RedisClient.Keys(Ctx, fmt.Sprintf("chunk:%s:*", videoID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks for video %s: %v", videoID, err)
+	}
+
+	type scoredChunk struct {
+		chunk TranscriptChunk
+		score float64
+	}
+	candidates := make([]scoredChunk, 0, len(keys))
+
+	for _, key := range keys {
+		fields, err := RedisClient.HGetAll(Ctx, key).Result()
+		if err != nil {
+			log.Printf("Failed to read chunk %s: %v", key, err)
+			continue
+		}
+
+		start, _ := strconv.ParseFloat(fields["start"], 64)
+		end, _ := strconv.ParseFloat(fields["end"], 64)
+		candidates = append(candidates, scoredChunk{
+			chunk: TranscriptChunk{VideoID: videoID, Start: start, End: end, Text: fields["text"]},
+			score: cosineSimilarity(queryVec, decodeVector(fields["vec"])),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+
+	chunks := make([]TranscriptChunk, k)
+	for i := 0; i < k; i++ {
+		chunks[i] = candidates[i].chunk
+	}
+
+	return chunks, nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return -1
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return -1
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func encodeVector(vec []float32) []byte {
+	buf := make([]byte, len(vec)*4)
+	for i, f := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(raw string) []float32 {
+	b := []byte(raw)
+	vec := make([]float32, len(b)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return vec
+}
+
+func toFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	case float64:
+		return val
+	default:
+		return 0
+	}
+}
+
+// embedText calls the embeddings endpoint for a single input string, using
+// DefaultClient's configured base URL and API key so it follows the same
+// OpenAI-compatible backend as every other OpenAI call in this package.
+func embedText(text string) ([]float32, error) {
+	requestBody := map[string]interface{}{
+		"model": embeddingModel,
+		"input": text,
+	}
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", DefaultClient.url("/embeddings"), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %v", err)
+	}
+	DefaultClient.setHeaders(req)
+
+	resp, err := DefaultClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send embedding request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to create embedding: %s", string(bodyBytes))
+	}
+
+	var embedResp struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %v", err)
+	}
+	if len(embedResp.Data) == 0 {
+		return nil, fmt.Errorf("embedding response contained no data")
+	}
+
+	return embedResp.Data[0].Embedding, nil
+}
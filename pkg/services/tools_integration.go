@@ -0,0 +1,112 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"Youtube-Learning-Mode-Ai-Service/pkg/tools"
+)
+
+func init() {
+	tools.Register("search_transcript", searchTranscriptTool)
+	tools.Register("get_chapters", getChaptersTool)
+}
+
+type searchTranscriptArgs struct {
+	Query string `json:"query"`
+}
+
+type transcriptMatch struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+func searchTranscriptTool(videoID, arguments string) (string, error) {
+	var args searchTranscriptArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("failed to parse search_transcript arguments: %v", err)
+	}
+
+	chunks, err := SearchChunks(videoID, args.Query, TopKChunks)
+	if err != nil {
+		return "", fmt.Errorf("failed to search transcript: %v", err)
+	}
+
+	matches := make([]transcriptMatch, len(chunks))
+	for i, chunk := range chunks {
+		matches[i] = transcriptMatch{Start: chunk.Start, End: chunk.End, Text: chunk.Text}
+	}
+
+	output, err := json.Marshal(matches)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal search_transcript output: %v", err)
+	}
+
+	return string(output), nil
+}
+
+type chapter struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"`
+}
+
+// chapterTargetSeconds is the approximate length of a chapter this tool
+// produces. There's no YouTube description available to this service to
+// pull real chapter markers from, and without an NLP pass over the
+// transcript there's no reliable way to detect an actual topic shift
+// either, so consecutive retrieval chunks are merged up to roughly this
+// many seconds instead of emitting one chapter per ~45s retrieval chunk.
+const chapterTargetSeconds = 180.0
+
+// getChaptersTool groups the video's indexed transcript chunks into
+// chapter-length windows and titles each with its first few words.
+func getChaptersTool(videoID, _ string) (string, error) {
+	chunks, err := ListChunks(videoID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list transcript chunks: %v", err)
+	}
+
+	chapters := chaptersFromChunks(chunks)
+
+	output, err := json.Marshal(chapters)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal get_chapters output: %v", err)
+	}
+
+	return string(output), nil
+}
+
+// chaptersFromChunks merges consecutive transcript chunks into chapters of
+// roughly chapterTargetSeconds each.
+func chaptersFromChunks(chunks []TranscriptChunk) []chapter {
+	var chapters []chapter
+	var texts []string
+	var chapterStart float64
+
+	for _, chunk := range chunks {
+		if len(texts) == 0 {
+			chapterStart = chunk.Start
+		}
+		texts = append(texts, chunk.Text)
+
+		if chunk.End-chapterStart >= chapterTargetSeconds {
+			chapters = append(chapters, chapter{Title: firstWords(strings.Join(texts, " "), 8), Start: chapterStart})
+			texts = nil
+		}
+	}
+	if len(texts) > 0 {
+		chapters = append(chapters, chapter{Title: firstWords(strings.Join(texts, " "), 8), Start: chapterStart})
+	}
+
+	return chapters
+}
+
+func firstWords(text string, n int) string {
+	words := strings.Fields(text)
+	if len(words) > n {
+		words = words[:n]
+	}
+	return strings.Join(words, " ")
+}
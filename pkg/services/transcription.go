@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// TranscriptSegment is a single timestamped slice of a transcript, as
+// returned by Whisper's verbose_json response format.
+type TranscriptSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+const transcriptCacheTTL = 7 * 24 * time.Hour
+
+// TranscribeVideo downloads a video's audio with yt-dlp and transcribes it
+// with Whisper, so callers don't need to supply a pre-extracted transcript.
+// Results are cached in Redis for a week to avoid re-downloading the audio.
+func TranscribeVideo(videoID string) ([]TranscriptSegment, error) {
+	if cached, err := getCachedTranscript(videoID); err == nil {
+		return cached, nil
+	}
+
+	audioPath, err := downloadAudio(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download audio for video %s: %v", videoID, err)
+	}
+	defer os.Remove(audioPath)
+
+	segments, err := transcribeAudio(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe audio for video %s: %v", videoID, err)
+	}
+
+	if err := cacheTranscript(videoID, segments); err != nil {
+		log.Printf("Failed to cache transcript for video %s: %v", videoID, err)
+	}
+
+	return segments, nil
+}
+
+func getCachedTranscript(videoID string) ([]TranscriptSegment, error) {
+	data, err := RedisClient.Get(Ctx, "transcript:"+videoID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []TranscriptSegment
+	if err := json.Unmarshal([]byte(data), &segments); err != nil {
+		return nil, fmt.Errorf("failed to decode cached transcript: %v", err)
+	}
+
+	return segments, nil
+}
+
+func cacheTranscript(videoID string, segments []TranscriptSegment) error {
+	data, err := json.Marshal(segments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript: %v", err)
+	}
+
+	return RedisClient.Set(Ctx, "transcript:"+videoID, data, transcriptCacheTTL).Err()
+}
+
+func downloadAudio(videoID string) (string, error) {
+	outputPath := filepath.Join(os.TempDir(), videoID+".mp3")
+	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
+
+	cmd := exec.Command("yt-dlp", "-x", "--audio-format", "mp3", "-o", outputPath, videoURL)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp failed: %v: %s", err, string(output))
+	}
+
+	return outputPath, nil
+}
+
+func transcribeAudio(audioPath string) ([]TranscriptSegment, error) {
+	file, err := os.Open(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %v", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("file", filepath.Base(audioPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %v", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("failed to copy audio into request: %v", err)
+	}
+
+	_ = writer.WriteField("model", "whisper-1")
+	_ = writer.WriteField("response_format", "verbose_json")
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart writer: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", DefaultClient.url("/audio/transcriptions"), &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	DefaultClient.setHeaders(req)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := DefaultClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to transcribe audio: %s", string(bodyBytes))
+	}
+
+	var transcriptionResp struct {
+		Segments []TranscriptSegment `json:"segments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&transcriptionResp); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription response: %v", err)
+	}
+
+	return transcriptionResp.Segments, nil
+}
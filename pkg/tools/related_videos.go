@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+func init() {
+	Register("get_related_videos", GetRelatedVideos)
+}
+
+// pipedInstances mirrors the round-robin instance list used by the external
+// search project this service borrows the pattern from.
+var pipedInstances = []string{
+	"https://pipedapi.kavin.rocks",
+	"https://api.piped.yt",
+	"https://piped-api.lunar.icu",
+}
+
+var pipedInstanceCursor uint64
+
+type getRelatedVideosArgs struct {
+	Topic string `json:"topic"`
+}
+
+type relatedVideo struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Channel string `json:"channel"`
+}
+
+// GetRelatedVideos searches a Piped API instance for videos about a topic,
+// round-robining across instances and failing over to the next one if the
+// current one is down.
+func GetRelatedVideos(_ string, arguments string) (string, error) {
+	var args getRelatedVideosArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("failed to parse get_related_videos arguments: %v", err)
+	}
+
+	start := atomic.AddUint64(&pipedInstanceCursor, 1)
+
+	var lastErr error
+	for i := 0; i < len(pipedInstances); i++ {
+		instance := pipedInstances[(int(start)+i)%len(pipedInstances)]
+
+		videos, err := searchPipedInstance(instance, args.Topic)
+		if err != nil {
+			log.Printf("Piped instance %s failed, trying next: %v", instance, err)
+			lastErr = err
+			continue
+		}
+
+		output, err := json.Marshal(videos)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal get_related_videos output: %v", err)
+		}
+		return string(output), nil
+	}
+
+	return "", fmt.Errorf("all piped instances failed: %v", lastErr)
+}
+
+func searchPipedInstance(instance, topic string) ([]relatedVideo, error) {
+	requestURL := fmt.Sprintf("%s/search?q=%s&filter=videos", instance, url.QueryEscape(topic))
+
+	resp, err := http.Get(requestURL)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var searchResp struct {
+		Items []struct {
+			Title        string `json:"title"`
+			URL          string `json:"url"`
+			UploaderName string `json:"uploaderName"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	videos := make([]relatedVideo, len(searchResp.Items))
+	for i, item := range searchResp.Items {
+		videos[i] = relatedVideo{
+			Title:   item.Title,
+			URL:     "https://youtube.com" + item.URL,
+			Channel: item.UploaderName,
+		}
+	}
+
+	return videos, nil
+}
@@ -0,0 +1,85 @@
+// Package tools implements the function-calling tools exposed to the
+// assistant (see RunAssistant's requires_action handling in pkg/services).
+// Packages that implement a tool register it from an init(), which keeps this
+// package free of dependencies on them and avoids import cycles.
+package tools
+
+import "fmt"
+
+// Handler dispatches a single function-calling tool call for a given video
+// and returns the JSON-encoded string to submit as that tool call's output.
+type Handler func(videoID string, arguments string) (string, error)
+
+var registry = map[string]Handler{}
+
+// Register adds (or replaces) the handler for a tool name.
+func Register(name string, handler Handler) {
+	registry[name] = handler
+}
+
+// Dispatch runs the named tool with its raw JSON arguments.
+func Dispatch(name, videoID, arguments string) (string, error) {
+	handler, ok := registry[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	return handler(videoID, arguments)
+}
+
+// Definitions returns the `tools` field to pass to the Assistants API when
+// creating an assistant.
+func Definitions() []map[string]interface{} {
+	return []map[string]interface{}{
+		searchTranscriptDefinition,
+		getChaptersDefinition,
+		getRelatedVideosDefinition,
+	}
+}
+
+var searchTranscriptDefinition = map[string]interface{}{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "search_transcript",
+		"description": "Search the video transcript for the moments most relevant to a query and return their timestamps.",
+		"parameters": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "What to search for in the transcript.",
+				},
+			},
+			"required": []string{"query"},
+		},
+	},
+}
+
+var getChaptersDefinition = map[string]interface{}{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "get_chapters",
+		"description": "List approximate chapter markers for the video (grouped by time, not by detected topic) with their starting timestamp.",
+		"parameters": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	},
+}
+
+var getRelatedVideosDefinition = map[string]interface{}{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "get_related_videos",
+		"description": "Find other videos related to a topic discussed in this video.",
+		"parameters": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"topic": map[string]interface{}{
+					"type":        "string",
+					"description": "The topic to find related videos for.",
+				},
+			},
+			"required": []string{"topic"},
+		},
+	},
+}